@@ -0,0 +1,104 @@
+package lgrep
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/juju/errors"
+)
+
+// ParseAggregation parses the shorthand aggregation syntax accepted
+// by SearchOptions.Aggregations - `kind:field[:arg]`, with `+`
+// separating a nested sub-aggregation - or a raw JSON aggregation
+// body. It returns the name the aggregation is registered under (and
+// later retrieved by), the top-level aggregation kind (used to know
+// how to read its result back out of the response), and its query
+// body.
+//
+// Examples:
+//
+//	terms:service
+//	date_histogram:@timestamp:1m
+//	stats:duration_ms
+//	terms:service+stats:duration_ms
+func ParseAggregation(spec string) (name string, kind string, agg QueryMap, err error) {
+	spec = strings.TrimSpace(spec)
+	if strings.HasPrefix(spec, "{") {
+		var raw QueryMap
+		if err := json.Unmarshal([]byte(spec), &raw); err != nil {
+			return "", "", nil, errors.Annotate(err, "invalid raw aggregation JSON")
+		}
+		for aggName, body := range raw {
+			sub, ok := body.(map[string]interface{})
+			if !ok {
+				return "", "", nil, errors.Errorf("aggregation %q has no body", aggName)
+			}
+			for aggKind := range sub {
+				return aggName, aggKind, QueryMap(sub), nil
+			}
+		}
+		return "", "", nil, errors.New("raw aggregation JSON has no top-level name")
+	}
+
+	levels := strings.Split(spec, "+")
+	name, kind, agg, err = parseAggregationLevel(levels[0])
+	if err != nil {
+		return "", "", nil, err
+	}
+	if len(levels) > 1 {
+		subName, _, subAgg, err := ParseAggregation(strings.Join(levels[1:], "+"))
+		if err != nil {
+			return "", "", nil, err
+		}
+		agg["aggs"] = QueryMap{subName: subAgg}
+	}
+	return name, kind, agg, nil
+}
+
+// SubAggregation returns the name and kind of spec's immediate nested
+// sub-aggregation - the part after a `+` in the shorthand syntax, as
+// in `terms:service+stats:duration_ms` - so that a top-level bucket's
+// embedded sub-aggregation can be looked up by name. ok is false when
+// spec has no sub-aggregation (including when spec is a raw JSON
+// aggregation body, which this doesn't attempt to introspect).
+func SubAggregation(spec string) (name string, kind string, ok bool, err error) {
+	spec = strings.TrimSpace(spec)
+	if strings.HasPrefix(spec, "{") {
+		return "", "", false, nil
+	}
+	levels := strings.SplitN(spec, "+", 2)
+	if len(levels) < 2 {
+		return "", "", false, nil
+	}
+	name, kind, _, err = ParseAggregation(levels[1])
+	if err != nil {
+		return "", "", false, err
+	}
+	return name, kind, true, nil
+}
+
+// parseAggregationLevel parses a single `kind:field[:arg]` shorthand
+// aggregation, without any nested sub-aggregations.
+func parseAggregationLevel(level string) (name string, kind string, agg QueryMap, err error) {
+	parts := strings.Split(level, ":")
+	if len(parts) < 2 {
+		return "", "", nil, errors.Errorf("invalid aggregation %q, expected kind:field[:arg]", level)
+	}
+	kind, field := parts[0], parts[1]
+	name = kind + "_" + strings.Replace(field, ".", "_", -1)
+
+	switch kind {
+	case "terms":
+		return name, kind, QueryMap{"terms": map[string]interface{}{"field": field}}, nil
+	case "stats":
+		return name, kind, QueryMap{"stats": map[string]interface{}{"field": field}}, nil
+	case "date_histogram":
+		interval := "1h"
+		if len(parts) > 2 {
+			interval = parts[2]
+		}
+		return name, kind, QueryMap{"date_histogram": map[string]interface{}{"field": field, "interval": interval}}, nil
+	default:
+		return "", "", nil, errors.Errorf("unsupported aggregation kind %q", kind)
+	}
+}
@@ -0,0 +1,129 @@
+package lgrep
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseAggregationShorthand(t *testing.T) {
+	cases := []struct {
+		spec     string
+		wantName string
+		wantKind string
+		wantAgg  QueryMap
+	}{
+		{
+			spec:     "terms:service",
+			wantName: "terms_service",
+			wantKind: "terms",
+			wantAgg:  QueryMap{"terms": map[string]interface{}{"field": "service"}},
+		},
+		{
+			spec:     "stats:duration_ms",
+			wantName: "stats_duration_ms",
+			wantKind: "stats",
+			wantAgg:  QueryMap{"stats": map[string]interface{}{"field": "duration_ms"}},
+		},
+		{
+			spec:     "date_histogram:@timestamp:1m",
+			wantName: "date_histogram_@timestamp",
+			wantKind: "date_histogram",
+			wantAgg:  QueryMap{"date_histogram": map[string]interface{}{"field": "@timestamp", "interval": "1m"}},
+		},
+		{
+			spec:     "date_histogram:@timestamp",
+			wantName: "date_histogram_@timestamp",
+			wantKind: "date_histogram",
+			wantAgg:  QueryMap{"date_histogram": map[string]interface{}{"field": "@timestamp", "interval": "1h"}},
+		},
+	}
+
+	for _, c := range cases {
+		name, kind, agg, err := ParseAggregation(c.spec)
+		if err != nil {
+			t.Errorf("ParseAggregation(%q) returned error: %v", c.spec, err)
+			continue
+		}
+		if name != c.wantName || kind != c.wantKind {
+			t.Errorf("ParseAggregation(%q) = (%q, %q), want (%q, %q)", c.spec, name, kind, c.wantName, c.wantKind)
+		}
+		if !reflect.DeepEqual(agg, c.wantAgg) {
+			t.Errorf("ParseAggregation(%q) agg = %#v, want %#v", c.spec, agg, c.wantAgg)
+		}
+	}
+}
+
+func TestParseAggregationNested(t *testing.T) {
+	name, kind, agg, err := ParseAggregation("terms:service+stats:duration_ms")
+	if err != nil {
+		t.Fatalf("ParseAggregation returned error: %v", err)
+	}
+	if name != "terms_service" || kind != "terms" {
+		t.Fatalf("ParseAggregation nested = (%q, %q), want (\"terms_service\", \"terms\")", name, kind)
+	}
+
+	sub, ok := agg["aggs"].(QueryMap)
+	if !ok {
+		t.Fatalf("ParseAggregation nested agg has no \"aggs\" QueryMap: %#v", agg)
+	}
+	if _, ok := sub["stats_duration_ms"]; !ok {
+		t.Errorf("ParseAggregation nested agg missing sub-aggregation \"stats_duration_ms\": %#v", sub)
+	}
+}
+
+func TestParseAggregationRawJSON(t *testing.T) {
+	name, kind, agg, err := ParseAggregation(`{"by_service":{"terms":{"field":"service"}}}`)
+	if err != nil {
+		t.Fatalf("ParseAggregation returned error: %v", err)
+	}
+	if name != "by_service" || kind != "terms" {
+		t.Errorf("ParseAggregation raw JSON = (%q, %q), want (\"by_service\", \"terms\")", name, kind)
+	}
+	want := QueryMap{"terms": map[string]interface{}{"field": "service"}}
+	if !reflect.DeepEqual(agg, want) {
+		t.Errorf("ParseAggregation raw JSON agg = %#v, want %#v", agg, want)
+	}
+}
+
+func TestParseAggregationInvalid(t *testing.T) {
+	cases := []string{
+		"",
+		"service",
+		"unsupported:field",
+	}
+	for _, spec := range cases {
+		if _, _, _, err := ParseAggregation(spec); err == nil {
+			t.Errorf("ParseAggregation(%q) should have returned an error", spec)
+		}
+	}
+}
+
+func TestSubAggregation(t *testing.T) {
+	name, kind, ok, err := SubAggregation("terms:service+stats:duration_ms")
+	if err != nil {
+		t.Fatalf("SubAggregation returned error: %v", err)
+	}
+	if !ok || name != "stats_duration_ms" || kind != "stats" {
+		t.Errorf("SubAggregation = (%q, %q, %v), want (\"stats_duration_ms\", \"stats\", true)", name, kind, ok)
+	}
+}
+
+func TestSubAggregationNone(t *testing.T) {
+	_, _, ok, err := SubAggregation("terms:service")
+	if err != nil {
+		t.Fatalf("SubAggregation returned error: %v", err)
+	}
+	if ok {
+		t.Error("SubAggregation should report ok=false when spec has no sub-aggregation")
+	}
+}
+
+func TestSubAggregationRawJSON(t *testing.T) {
+	_, _, ok, err := SubAggregation(`{"by_service":{"terms":{"field":"service"}}}`)
+	if err != nil {
+		t.Fatalf("SubAggregation returned error: %v", err)
+	}
+	if ok {
+		t.Error("SubAggregation should report ok=false for a raw JSON aggregation body")
+	}
+}
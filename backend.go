@@ -0,0 +1,146 @@
+package lgrep
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/juju/errors"
+)
+
+// BackendVersion identifies the wire-protocol generation of the
+// Elasticsearch (or OpenSearch) cluster being talked to.
+//
+// Only BackendV2 is implemented. lgrep's client is built on
+// gopkg.in/olivere/elastic.v3, which only speaks Elasticsearch 2.x's
+// wire protocol; talking to a 5.x/6.x/7.x cluster needs a client
+// built against a newer olivere/elastic (v5) or the official
+// go-elasticsearch client (v7), neither of which this build vendors.
+// BackendVersion and the Backend interface exist so that support can
+// be added behind this seam later without reworking callers - they
+// are not, themselves, multi-version support.
+type BackendVersion int
+
+const (
+	// BackendAuto requests that the backend be auto-detected from
+	// the cluster rather than specified explicitly.
+	BackendAuto BackendVersion = 0
+	// BackendV2 speaks the Elasticsearch 2.x wire protocol, the only
+	// protocol currently implemented (via gopkg.in/olivere/elastic.v3).
+	BackendV2 BackendVersion = 2
+)
+
+// Backend hides the differences between Elasticsearch's major
+// versions - query DSL naming (`filtered` vs `bool`), mapping type
+// removal, and so on - behind a common interface, so the rest of the
+// package can build version-correct requests without caring which
+// cluster it's ultimately talking to. backendV2 is the only
+// implementation today; see the BackendVersion doc comment.
+type Backend interface {
+	// Version reports the Elasticsearch major version this backend
+	// speaks (see the BackendV* constants).
+	Version() BackendVersion
+	// MatchAllQuery returns this version's "match everything" query
+	// body.
+	MatchAllQuery() QueryMap
+	// BoolQuery builds this version's boolean query body from must
+	// and filter clauses.
+	BoolQuery(must, filter []QueryMap) QueryMap
+}
+
+// NewBackend constructs the Backend for the given version. Pass
+// BackendAuto to detect the cluster's version by querying endpoint
+// instead of specifying one explicitly. Detecting or requesting
+// anything other than a 2.x cluster returns an error - see the
+// BackendVersion doc comment for why.
+func NewBackend(version BackendVersion, endpoint string) (Backend, error) {
+	if version == BackendAuto {
+		detected, err := DetectBackendVersion(endpoint)
+		if err != nil {
+			return nil, errors.Annotate(err, "could not detect Elasticsearch version")
+		}
+		version = detected
+	}
+
+	switch version {
+	case BackendV2:
+		return backendV2{}, nil
+	default:
+		return nil, errors.Errorf("Elasticsearch %d.x is not supported by this build of lgrep (only %d.x, via gopkg.in/olivere/elastic.v3)", version, BackendV2)
+	}
+}
+
+// DetectBackendVersion queries endpoint's root document (`GET /`) and
+// returns the cluster's major version.
+func DetectBackendVersion(endpoint string) (BackendVersion, error) {
+	resp, err := http.Get(endpoint)
+	if err != nil {
+		return BackendAuto, errors.Annotate(err, "could not reach Elasticsearch to detect its version")
+	}
+	defer resp.Body.Close()
+
+	var root struct {
+		Version struct {
+			Number string `json:"number"`
+		} `json:"version"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&root); err != nil {
+		return BackendAuto, errors.Annotate(err, "could not decode Elasticsearch root response")
+	}
+
+	major, err := strconv.Atoi(strings.SplitN(root.Version.Number, ".", 2)[0])
+	if err != nil {
+		return BackendAuto, errors.Annotatef(err, "could not parse Elasticsearch version %q", root.Version.Number)
+	}
+	return BackendVersion(major), nil
+}
+
+// backendV2 implements Backend for the Elasticsearch 2.x query DSL,
+// as produced by gopkg.in/olivere/elastic.v3.
+type backendV2 struct{}
+
+func (backendV2) Version() BackendVersion { return BackendV2 }
+
+func (backendV2) MatchAllQuery() QueryMap {
+	return QueryMap{"match_all": map[string]interface{}{}}
+}
+
+func (backendV2) BoolQuery(must, filter []QueryMap) QueryMap {
+	clause := map[string]interface{}{}
+	if len(must) > 0 {
+		clause["must"] = queryMapsToInterfaces(must)
+	}
+	if len(filter) > 0 {
+		clause["filter"] = queryMapsToInterfaces(filter)
+	}
+	return QueryMap{"bool": clause}
+}
+
+func queryMapsToInterfaces(queries []QueryMap) []interface{} {
+	out := make([]interface{}, len(queries))
+	for i, q := range queries {
+		out[i] = map[string]interface{}(q)
+	}
+	return out
+}
+
+// queryStringQueryMap builds a lucene query_string query body, used
+// as the `must` clause when composing a Backend.BoolQuery.
+func queryStringQueryMap(q string) QueryMap {
+	return QueryMap{"query_string": map[string]interface{}{"query": q}}
+}
+
+// rangeQueryMap builds a range query body over field bounded by
+// [gte, lte], used as a `filter` clause when composing a
+// Backend.BoolQuery.
+func rangeQueryMap(field string, gte, lte interface{}) QueryMap {
+	bounds := map[string]interface{}{}
+	if gte != nil {
+		bounds["gte"] = gte
+	}
+	if lte != nil {
+		bounds["lte"] = lte
+	}
+	return QueryMap{"range": map[string]interface{}{field: bounds}}
+}
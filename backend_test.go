@@ -0,0 +1,40 @@
+package lgrep
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDetectBackendVersion(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"version":{"number":"2.4.1"}}`)
+	}))
+	defer srv.Close()
+
+	version, err := DetectBackendVersion(srv.URL)
+	if err != nil {
+		t.Fatalf("DetectBackendVersion returned error: %v", err)
+	}
+	if version != BackendV2 {
+		t.Errorf("DetectBackendVersion = %v, want %v", version, BackendV2)
+	}
+}
+
+func TestDetectBackendVersionUnreachable(t *testing.T) {
+	if _, err := DetectBackendVersion("http://127.0.0.1:0"); err == nil {
+		t.Error("DetectBackendVersion should return an error when the endpoint can't be reached")
+	}
+}
+
+func TestDetectBackendVersionBadResponse(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `not json`)
+	}))
+	defer srv.Close()
+
+	if _, err := DetectBackendVersion(srv.URL); err == nil {
+		t.Error("DetectBackendVersion should return an error for an undecodable response")
+	}
+}
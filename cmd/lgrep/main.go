@@ -1,12 +1,13 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
 	"flag"
 	"fmt"
 	"io/ioutil"
 	"net/url"
 	"os"
+	"os/signal"
 	"strings"
 
 	log "github.com/Sirupsen/logrus"
@@ -53,6 +54,58 @@ var (
 			Name:  "debug, D",
 			Usage: "Debug lgrep run with verbose logging",
 		},
+
+		cli.IntFlag{
+			Name:   "es-version",
+			Usage:  "Elasticsearch major version to speak to, auto-detected if not set (only 2.x is currently supported)",
+			EnvVar: "LGREP_ES_VERSION",
+		},
+
+		cli.StringFlag{
+			Name:   "username",
+			Usage:  "Username for HTTP basic auth",
+			EnvVar: "LGREP_USERNAME",
+		},
+		cli.StringFlag{
+			Name:   "password",
+			Usage:  "Password for HTTP basic auth",
+			EnvVar: "LGREP_PASSWORD",
+		},
+		cli.StringFlag{
+			Name:   "api-key",
+			Usage:  "Elasticsearch API key, sent as 'Authorization: ApiKey <key>'",
+			EnvVar: "LGREP_API_KEY",
+		},
+		cli.StringFlag{
+			Name:   "bearer",
+			Usage:  "Bearer token, sent as 'Authorization: Bearer <token>'",
+			EnvVar: "LGREP_BEARER",
+		},
+		cli.StringFlag{
+			Name:   "cloud-id",
+			Usage:  "Elastic Cloud ID, resolved into the cluster's endpoint (overrides --endpoint)",
+			EnvVar: "LGREP_CLOUD_ID",
+		},
+		cli.StringFlag{
+			Name:   "ca-cert",
+			Usage:  "CA certificate file to verify the server's certificate against",
+			EnvVar: "LGREP_CA_CERT",
+		},
+		cli.StringFlag{
+			Name:   "client-cert",
+			Usage:  "Client certificate file for mutual TLS, requires --client-key",
+			EnvVar: "LGREP_CLIENT_CERT",
+		},
+		cli.StringFlag{
+			Name:   "client-key",
+			Usage:  "Client private key file for mutual TLS, requires --client-cert",
+			EnvVar: "LGREP_CLIENT_KEY",
+		},
+		cli.BoolFlag{
+			Name:   "insecure-skip-verify",
+			Usage:  "Disable TLS certificate verification",
+			EnvVar: "LGREP_INSECURE_SKIP_VERIFY",
+		},
 	}
 
 	// QueryFlags apply to runs that query with lgrep
@@ -79,6 +132,50 @@ var (
 			Name:  "tabulate, T",
 			Usage: "Tabulate the data into columns",
 		},
+		cli.StringFlag{
+			Name:  "output, o",
+			Usage: "Write structured output instead of --format: 'ndjson' or 'csv' (csv requires --query-fields/-Qc)",
+		},
+		cli.BoolFlag{
+			Name:  "flatten",
+			Usage: "Flatten nested _source objects into dot-keyed fields (e.g. http.response.status_code) before formatting or --output",
+		},
+		cli.BoolFlag{
+			Name:  "all, A",
+			Usage: "Stream every matching result using the scroll API, ignoring --size",
+		},
+		cli.StringFlag{
+			Name:  "from",
+			Usage: "Start of the time range to search, as RFC3339 or a relative expression (now, now-1h, now-15m)",
+		},
+		cli.StringFlag{
+			Name:  "to",
+			Usage: "End of the time range to search, as RFC3339 or a relative expression (now, now-1h, now-15m)",
+			Value: "now",
+		},
+		cli.StringFlag{
+			Name:  "time-field",
+			Usage: "Document field compared against when --from/--to are set",
+			Value: lgrep.DefaultTimeField,
+		},
+		cli.StringFlag{
+			Name:  "index-pattern",
+			Usage: "Go reference-time layout used to expand --from/--to into concrete indices",
+			Value: lgrep.DefaultIndexPattern,
+		},
+		cli.BoolFlag{
+			Name:  "follow, F",
+			Usage: "Follow newly matching results like `tail -f`, polling until interrupted",
+		},
+		cli.DurationFlag{
+			Name:  "poll-interval",
+			Usage: "How often to poll for new results with --follow",
+			Value: lgrep.DefaultPollInterval,
+		},
+		cli.StringFlag{
+			Name:  "agg, Qa",
+			Usage: "Aggregate instead of listing hits: kind:field[:arg] (terms:service, date_histogram:@timestamp:1m, stats:duration_ms), nested with +, or a raw JSON aggregation body",
+		},
 		cli.BoolFlag{
 			Name:   "query-debug, QD",
 			Usage:  "Log query sent to the server",
@@ -171,79 +268,189 @@ func RunPrepareApp(c *cli.Context) (err error) {
 		return cli.NewExitError("No query provided", 3)
 	}
 
+	if output := c.String("output"); output != "" && output != "ndjson" && output != "csv" {
+		return cli.NewExitError("--output must be 'ndjson' or 'csv'", 1)
+	}
+	if c.String("output") == "csv" && c.String("query-fields") == "" {
+		return cli.NewExitError("--output csv requires --query-fields/-Qc", 1)
+	}
+
 	return err
 }
 
 func RunQuery(c *cli.Context) (err error) {
 	var (
-		endpoint    = c.String("endpoint")
-		queryFile   = c.String("query-file")
-		querySize   = c.Int("count")
-		queryIndex  = c.String("query-index")
-		queryDebug  = c.Bool("query-debug")
-		queryFields = strings.Split(c.String("query-fields"), ",")
-		query       = strings.Join(c.Args(), " ")
+		endpoint   = c.String("endpoint")
+		queryFile  = c.String("query-file")
+		queryIndex = c.String("query-index")
+		queryDebug = c.Bool("query-debug")
+		query      = strings.Join(c.Args(), " ")
 
 		format    = c.String("format")
 		formatRaw = c.Bool("raw-json")
-
-		// Results from the executed search
-		results []*json.RawMessage
 	)
 
-	l, err := lgrep.New(endpoint)
+	var queryFields []string
+	if fields := c.String("query-fields"); fields != "" {
+		queryFields = strings.Split(fields, ",")
+	}
+
+	auth := lgrep.AuthOptions{
+		Username:           c.String("username"),
+		Password:           c.String("password"),
+		APIKey:             c.String("api-key"),
+		BearerToken:        c.String("bearer"),
+		CloudID:            c.String("cloud-id"),
+		CACertFile:         c.String("ca-cert"),
+		ClientCertFile:     c.String("client-cert"),
+		ClientKeyFile:      c.String("client-key"),
+		InsecureSkipVerify: c.Bool("insecure-skip-verify"),
+	}
+
+	l, err := lgrep.NewWithAuth(endpoint, lgrep.BackendVersion(c.Int("es-version")), auth)
 	if err != nil {
 		return err
 	}
 
-	l.Debug = queryDebug
+	spec := lgrep.DefaultSpec
+	spec.Size = c.Int("size")
+	spec.Index = queryIndex
+	spec.Fields = queryFields
+	spec.QueryDebug = queryDebug
+	spec.Scroll = c.Bool("all")
+	spec.TimeField = c.String("time-field")
+	spec.IndexPattern = c.String("index-pattern")
+	spec.Aggregations = c.String("agg")
+
+	var stream *lgrep.SearchStream
+	if c.Bool("follow") {
+		if query == "" {
+			return cli.NewExitError("No query provided", 3)
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		sigs := make(chan os.Signal, 1)
+		signal.Notify(sigs, os.Interrupt)
+		go func() {
+			<-sigs
+			cancel()
+		}()
 
-	if c.IsSet("query-file") {
-		var (
-			f *os.File
-			d []byte
-		)
-		f, err = os.Open(queryFile)
+		topts := lgrep.DefaultTailOptions
+		topts.PollInterval = c.Duration("poll-interval")
+
+		stream, err = l.TailStream(ctx, query, &spec, &topts)
+		if err != nil {
+			return err
+		}
+	} else if c.IsSet("query-file") {
+		f, err := os.Open(queryFile)
 		if err != nil {
 			return errors.Annotate(err, "Could not open the provided query file")
 		}
-		d, err = ioutil.ReadAll(f)
+		defer f.Close()
+
+		d, err := ioutil.ReadAll(f)
 		if err != nil {
 			return errors.Annotate(err, "Could not read the provided query file")
 		}
-		results, err = l.SearchWithSource(d)
+		stream, err = l.SearchWithSourceStream(d, &spec)
+		if err != nil {
+			return err
+		}
+	} else if query != "" && (c.IsSet("from") || c.IsSet("to")) {
+		t1, err := lgrep.ParseTime(c.String("from"))
+		if err != nil {
+			return errors.Annotate(err, "Could not parse --from")
+		}
+		t2, err := lgrep.ParseTime(c.String("to"))
+		if err != nil {
+			return errors.Annotate(err, "Could not parse --to")
+		}
+		stream, err = l.SearchTimerangeStream(query, &spec, t1, t2)
+		if err != nil {
+			return err
+		}
+	} else if query != "" {
+		stream, err = l.SimpleSearchStream(query, &spec)
+		if err != nil {
+			return err
+		}
+	} else {
+		return cli.NewExitError("No query provided", 3)
 	}
+	defer stream.Close()
 
-	if query != "" {
-		results, err = l.SimpleSearch(query, queryIndex, querySize)
-	}
+	tabulate := c.Bool("tabulate")
+	output := c.String("output")
+	flatten := c.Bool("flatten")
+	var tabulated []lgrep.Result
+	var csvResults []lgrep.Result
 
-	if err != nil {
-		return err
-	}
+	count := 0
+	for result := range stream.Results {
+		count++
 
-	if len(results) == 0 {
-		log.Warn("0 results returned")
-		return
+		if tabulate {
+			tabulated = append(tabulated, result)
+			continue
+		}
+
+		if output == "csv" {
+			csvResults = append(csvResults, result)
+			continue
+		}
+
+		if output == "ndjson" {
+			if err := lgrep.WriteNDJSON(os.Stdout, []lgrep.Result{result}, queryFields, flatten); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if flatten {
+			result, err = lgrep.FlattenResult(result)
+			if err != nil {
+				return err
+			}
+		}
+
+		if formatRaw {
+			fmt.Printf("%s\n", result)
+			continue
+		}
+
+		msgs, err := lgrep.Format([]lgrep.Result{result}, format)
+		if err != nil {
+			return err
+		}
+		for i := range msgs {
+			fmt.Println(msgs[i])
+		}
 	}
 
-	if formatRaw {
-		if len(queryFields) > 0 {
-			log.Error("Field selection and raw output is unsupported at this time")
-			return nil
+	if tabulate {
+		if err := lgrep.Tabulate(os.Stdout, tabulated); err != nil {
+			return err
 		}
-		for i := range results {
-			fmt.Printf("%s\n", results[i])
+	}
+
+	if output == "csv" {
+		if err := lgrep.WriteCSV(os.Stdout, csvResults, queryFields, flatten); err != nil {
+			return err
 		}
-		return
 	}
 
-	msgs, err := lgrep.Format(results, format)
-	if err != nil {
-		return err
+	select {
+	case err := <-stream.Errors:
+		if err != nil {
+			return err
+		}
+	default:
 	}
-	for i := range msgs {
-		fmt.Println(msgs[i])
+
+	if count == 0 {
+		log.Warn("0 results returned")
 	}
 	return nil
 }
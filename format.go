@@ -0,0 +1,82 @@
+package lgrep
+
+import (
+	"bytes"
+	"encoding/json"
+	"text/template"
+	"time"
+
+	"github.com/juju/errors"
+)
+
+// formatFuncs provides the template helper functions available to
+// result formatting templates, such as `ftime` used to reformat
+// timestamp fields.
+var formatFuncs = template.FuncMap{
+	"ftime": formatTime,
+}
+
+// formatTime reformats an RFC3339 timestamp string using the given
+// reference-time layout (see the lgrep command's usage text for
+// examples).
+func formatTime(layout, value string) (string, error) {
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return "", errors.Annotate(err, "could not parse time value")
+	}
+	return t.Format(layout), nil
+}
+
+// Format renders each result using the given text/template format
+// string, returning one rendered line per result.
+func Format(results []Result, format string) (lines []string, err error) {
+	tmpl, err := template.New("format").Funcs(formatFuncs).Parse(format)
+	if err != nil {
+		return nil, errors.Annotate(err, "invalid format template")
+	}
+
+	for _, result := range results {
+		data, err := resultData(result)
+		if err != nil {
+			return lines, err
+		}
+
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, data); err != nil {
+			return lines, errors.Annotate(err, "could not render result")
+		}
+		lines = append(lines, buf.String())
+	}
+	return lines, nil
+}
+
+// resultData normalizes a Result into the map shape formatting
+// templates are executed against.
+func resultData(result Result) (map[string]interface{}, error) {
+	switch r := result.(type) {
+	case SourceResult:
+		var data map[string]interface{}
+		if err := json.Unmarshal(r, &data); err != nil {
+			return nil, errors.Annotate(err, "could not decode result source")
+		}
+		return data, nil
+	case FieldResult:
+		return map[string]interface{}(r), nil
+	case HitResult:
+		if r.Source == nil {
+			return nil, errors.New("nil document returned")
+		}
+		return resultData(SourceResult(*r.Source))
+	case Bucket:
+		data := map[string]interface{}{
+			"key":       r.Key,
+			"doc_count": r.DocCount,
+		}
+		for k, v := range r.Metrics {
+			data[k] = v
+		}
+		return data, nil
+	default:
+		return nil, errors.Errorf("unsupported result type %T", result)
+	}
+}
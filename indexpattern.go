@@ -0,0 +1,44 @@
+package lgrep
+
+import "time"
+
+// DefaultIndexPattern is the index pattern used when
+// SearchOptions.IndexPattern is left unset. It lays out one index per
+// day, which is the layout produced by Logstash's default setup.
+const DefaultIndexPattern = "logstash-2006.01.02"
+
+// DefaultTimeField is the document field compared against when
+// searching a time range, used when SearchOptions.TimeField is left
+// unset.
+const DefaultTimeField = "@timestamp"
+
+// resolveIndices expands an index pattern into the concrete indices
+// that cover [t1, t2]. The pattern is a Go reference-time layout (see
+// DefaultIndexPattern), formatted once per day in the range. Patterns
+// that don't vary with time, such as a bare index name or a wildcard
+// like "logs-*", are passed through untouched.
+func resolveIndices(pattern string, t1, t2 time.Time) []string {
+	if pattern == "" {
+		pattern = DefaultIndexPattern
+	}
+	if t1.Format(pattern) == t2.Format(pattern) {
+		return []string{t1.Format(pattern)}
+	}
+
+	if t2.Before(t1) {
+		t1, t2 = t2, t1
+	}
+
+	var indices []string
+	seen := make(map[string]bool)
+	day := time.Date(t1.Year(), t1.Month(), t1.Day(), 0, 0, 0, 0, t1.Location())
+	for !day.After(t2) {
+		index := day.Format(pattern)
+		if !seen[index] {
+			seen[index] = true
+			indices = append(indices, index)
+		}
+		day = day.AddDate(0, 0, 1)
+	}
+	return indices
+}
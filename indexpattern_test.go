@@ -0,0 +1,61 @@
+package lgrep
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestResolveIndicesStaticPattern(t *testing.T) {
+	t1 := time.Date(2016, 4, 29, 0, 0, 0, 0, time.UTC)
+	t2 := time.Date(2016, 5, 2, 0, 0, 0, 0, time.UTC)
+
+	got := resolveIndices("logs-*", t1, t2)
+	want := []string{"logs-*"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("resolveIndices(static pattern) = %v, want %v", got, want)
+	}
+}
+
+func TestResolveIndicesSameDay(t *testing.T) {
+	t1 := time.Date(2016, 4, 29, 1, 0, 0, 0, time.UTC)
+	t2 := time.Date(2016, 4, 29, 23, 0, 0, 0, time.UTC)
+
+	got := resolveIndices(DefaultIndexPattern, t1, t2)
+	want := []string{"logstash-2016.04.29"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("resolveIndices(same day) = %v, want %v", got, want)
+	}
+}
+
+func TestResolveIndicesSpansDays(t *testing.T) {
+	t1 := time.Date(2016, 4, 29, 23, 0, 0, 0, time.UTC)
+	t2 := time.Date(2016, 5, 1, 1, 0, 0, 0, time.UTC)
+
+	got := resolveIndices(DefaultIndexPattern, t1, t2)
+	want := []string{"logstash-2016.04.29", "logstash-2016.04.30", "logstash-2016.05.01"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("resolveIndices(spans days) = %v, want %v", got, want)
+	}
+}
+
+func TestResolveIndicesSwapsReversedRange(t *testing.T) {
+	t1 := time.Date(2016, 5, 1, 0, 0, 0, 0, time.UTC)
+	t2 := time.Date(2016, 4, 29, 0, 0, 0, 0, time.UTC)
+
+	got := resolveIndices(DefaultIndexPattern, t1, t2)
+	want := []string{"logstash-2016.04.29", "logstash-2016.04.30", "logstash-2016.05.01"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("resolveIndices(reversed range) = %v, want %v", got, want)
+	}
+}
+
+func TestResolveIndicesDefaultsPattern(t *testing.T) {
+	t1 := time.Date(2016, 4, 29, 0, 0, 0, 0, time.UTC)
+
+	got := resolveIndices("", t1, t1)
+	want := []string{"logstash-2016.04.29"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("resolveIndices(default pattern) = %v, want %v", got, want)
+	}
+}
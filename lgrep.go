@@ -4,7 +4,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"net/url"
 	"os"
+	"strings"
 	"time"
 
 	log "github.com/Sirupsen/logrus"
@@ -27,12 +29,49 @@ type LGrep struct {
 	*elastic.Client
 	// Endpoint to use when working with Elasticsearch
 	Endpoint string
+	// Backend hides the query DSL differences between Elasticsearch
+	// versions. Defaults to a v2-speaking backend if not set by New.
+	Backend Backend
 }
 
-// New creates a new lgrep client.
+// New creates a new lgrep client, auto-detecting the Elasticsearch
+// version served at endpoint. Use NewWithVersion or NewWithAuth for
+// more control.
 func New(endpoint string) (lg LGrep, err error) {
+	return NewWithAuth(endpoint, BackendAuto, AuthOptions{})
+}
+
+// NewWithVersion creates a new lgrep client against a backend of the
+// given version, skipping auto-detection when version is not
+// BackendAuto.
+func NewWithVersion(endpoint string, version BackendVersion) (lg LGrep, err error) {
+	return NewWithAuth(endpoint, version, AuthOptions{})
+}
+
+// NewWithAuth creates a new lgrep client authenticated and
+// transported per auth - basic auth, an API key, a bearer token,
+// and/or TLS (including mTLS) - against endpoint, or against the
+// cluster named by auth.CloudID when set.
+func NewWithAuth(endpoint string, version BackendVersion, auth AuthOptions) (lg LGrep, err error) {
+	if auth.CloudID != "" {
+		endpoint, err = ParseCloudID(auth.CloudID)
+		if err != nil {
+			return lg, err
+		}
+	}
 	lg = LGrep{Endpoint: endpoint}
-	lg.Client, err = elastic.NewClient(elastic.SetURL(endpoint))
+
+	httpClient, err := newHTTPClient(auth)
+	if err != nil {
+		return lg, err
+	}
+
+	lg.Client, err = elastic.NewClient(elastic.SetURL(endpoint), elastic.SetHttpClient(httpClient))
+	if err != nil {
+		return lg, err
+	}
+
+	lg.Backend, err = NewBackend(version, endpoint)
 	return lg, err
 }
 
@@ -44,16 +83,19 @@ func (l LGrep) SimpleSearchStream(q string, spec *SearchOptions) (stream *Search
 	search, source := l.NewSearch()
 	search = SearchWithLucene(search, q)
 	if spec != nil {
-		// If user wants 0 then they're really not looking to get any
-		// results, don't execute.
-		if spec.Size == 0 {
+		// If user wants 0 and isn't just requesting an aggregation,
+		// they're really not looking to get any results, don't
+		// execute.
+		if spec.Size == 0 && spec.Aggregations == "" {
 			return nil, ErrZeroSize
 		}
 	} else {
 		spec = &DefaultSpec
 	}
 
-	spec.configureSearch(search)
+	if err := spec.configureSearch(search); err != nil {
+		return nil, err
+	}
 
 	// Spit out the query that will be sent.
 	if spec.QueryDebug {
@@ -93,7 +135,9 @@ func (l LGrep) SearchWithSourceStream(raw interface{}, spec *SearchOptions) (str
 		spec = &DefaultSpec
 	}
 
-	spec.configureSearch(search)
+	if err := spec.configureSearch(search); err != nil {
+		return nil, err
+	}
 	var query elastic.Query
 	switch v := raw.(type) {
 	case json.RawMessage:
@@ -142,7 +186,6 @@ func (l LGrep) SearchWithSource(raw interface{}, spec *SearchOptions) (results [
 	return stream.All()
 }
 
-//
 func extractResult(hit *elastic.SearchHit, spec SearchOptions) (result Result, err error) {
 	if spec.RawResult {
 		return HitResult(*hit), nil
@@ -159,6 +202,18 @@ func extractResult(hit *elastic.SearchHit, spec SearchOptions) (result Result, e
 // consumeResults ingests the results from the returned data and
 // transforms them into Result's.
 func consumeResults(res *elastic.SearchResult, spec SearchOptions) (results []Result, err error) {
+	if spec.Aggregations != "" {
+		name, kind, _, err := ParseAggregation(spec.Aggregations)
+		if err != nil {
+			return nil, err
+		}
+		subName, subKind, _, err := SubAggregation(spec.Aggregations)
+		if err != nil {
+			return nil, err
+		}
+		return consumeAggregations(res, name, kind, subName, subKind)
+	}
+
 	for _, doc := range res.Hits.Hits {
 		result, err := extractResult(doc, spec)
 		if err != nil {
@@ -169,10 +224,122 @@ func consumeResults(res *elastic.SearchResult, spec SearchOptions) (results []Re
 	return results, nil
 }
 
-// SearchTimerange will return occurrences of the matching search in
-// the timeframe provided.
-func (l LGrep) SearchTimerange(search string, count int, t1 time.Time, t2 time.Time) {
+// SearchTimerangeStream runs a lucene search restricted to the given
+// timeframe, resolving spec.IndexPattern into the concrete indices
+// that cover [t1, t2] and filtering on spec.TimeField.
+func (l LGrep) SearchTimerangeStream(search string, spec *SearchOptions, t1 time.Time, t2 time.Time) (stream *SearchStream, err error) {
+	if search == "" {
+		return nil, ErrEmptySearch
+	}
+	if spec == nil {
+		spec = &DefaultSpec
+	}
+
+	timeField := spec.TimeField
+	if timeField == "" {
+		timeField = DefaultTimeField
+	}
+
+	rangeSpec := *spec
+	rangeSpec.Index = strings.Join(resolveIndices(spec.IndexPattern, t1, t2), ",")
 
+	backend := l.Backend
+	if backend == nil {
+		backend = backendV2{}
+	}
+	query := backend.BoolQuery(
+		[]QueryMap{queryStringQueryMap(search)},
+		[]QueryMap{rangeQueryMap(timeField, t1, t2)},
+	)
+
+	searchSvc, source := l.NewSearch()
+	searchSvc = searchSvc.Query(query)
+	if err := rangeSpec.configureSearch(searchSvc); err != nil {
+		return nil, err
+	}
+
+	if rangeSpec.QueryDebug {
+		printQueryDebug(os.Stderr, query)
+	}
+
+	if !rangeSpec.QuerySkipValidate {
+		if _, err := l.validate(query, rangeSpec); err != nil {
+			return nil, err
+		}
+	}
+
+	return l.execute(searchSvc, source, rangeSpec)
+}
+
+// SearchTimerange returns the occurrences of the matching search in
+// the timeframe provided, searching across whatever indices
+// DefaultIndexPattern expands to for [t1, t2].
+func (l LGrep) SearchTimerange(search string, count int, t1 time.Time, t2 time.Time) (results []Result, err error) {
+	spec := DefaultSpec
+	spec.Size = count
+	stream, err := l.SearchTimerangeStream(search, &spec, t1, t2)
+	if err != nil {
+		return nil, err
+	}
+	return stream.All()
+}
+
+// sourcer is satisfied by anything that can produce an Elasticsearch
+// query/search source body, which covers both *elastic.SearchSource
+// and the elastic.Query implementations (including QueryMap).
+type sourcer interface {
+	Source() (interface{}, error)
+}
+
+// validateQueryResponse is the body of Elasticsearch's Validate Query
+// API (`_validate/query`). gopkg.in/olivere/elastic.v3 doesn't wrap
+// this endpoint, so validate builds and decodes the request itself.
+type validateQueryResponse struct {
+	Valid        bool `json:"valid"`
+	Explanations []struct {
+		Index       string `json:"index"`
+		Valid       bool   `json:"valid"`
+		Explanation string `json:"explanation"`
+		Error       string `json:"error"`
+	} `json:"explanations"`
+}
+
+// validate submits the query to Elasticsearch's Validate Query API
+// before executing it, so that malformed queries are surfaced before
+// a search is actually run.
+func (l LGrep) validate(query sourcer, spec SearchOptions) (*validateQueryResponse, error) {
+	src, err := query.Source()
+	if err != nil {
+		return nil, errors.Annotate(err, "could not generate query source for validation")
+	}
+	// A full search body (e.g. from an *elastic.SearchSource) carries
+	// its query under "query"; only that part is validated.
+	if m, ok := src.(map[string]interface{}); ok {
+		if q, ok := m["query"]; ok {
+			src = q
+		}
+	}
+
+	path := "/_validate/query"
+	if spec.Index != "" {
+		path = "/" + spec.Index + path
+	}
+	params := url.Values{"explain": []string{"true"}}
+	body := map[string]interface{}{"query": src}
+
+	httpResp, err := l.Client.PerformRequest("POST", path, params, body)
+	if err != nil {
+		return nil, errors.Annotate(err, "query validation request failed")
+	}
+
+	resp := new(validateQueryResponse)
+	if err := json.Unmarshal(httpResp.Body, resp); err != nil {
+		return nil, errors.Annotate(err, "could not decode query validation response")
+	}
+	if !resp.Valid {
+		return resp, errors.New("query is invalid")
+	}
+	return resp, nil
 }
 
 // NewSearch initializes a new search object along with a func to
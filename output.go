@@ -0,0 +1,171 @@
+package lgrep
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/juju/errors"
+)
+
+// flattenDoc turns a nested document into a single-level map with
+// dot-joined keys, e.g. {"http":{"method":"GET"}} becomes
+// {"http.method":"GET"}.
+func flattenDoc(data map[string]interface{}) map[string]interface{} {
+	flat := make(map[string]interface{})
+	flattenInto(flat, "", data)
+	return flat
+}
+
+func flattenInto(flat map[string]interface{}, prefix string, data map[string]interface{}) {
+	for k, v := range data {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+		if nested, ok := v.(map[string]interface{}); ok {
+			flattenInto(flat, key, nested)
+			continue
+		}
+		flat[key] = v
+	}
+}
+
+// FlattenResult re-encodes a result's document with its nested
+// objects collapsed into dot-keyed fields (see flattenDoc), so that
+// a later Format or field projection can address a nested value like
+// "http.response.status_code" as a single top-level field.
+func FlattenResult(result Result) (Result, error) {
+	data, err := resultData(result)
+	if err != nil {
+		return nil, err
+	}
+	flat, err := json.Marshal(flattenDoc(data))
+	if err != nil {
+		return nil, errors.Annotate(err, "could not re-encode flattened result")
+	}
+	return SourceResult(flat), nil
+}
+
+// lookupPath resolves a dotted field path (e.g. "http.method")
+// against a result document. It tries path as a literal top-level key
+// first - which is what flattenDoc produces - before falling back to
+// descending through nested objects one path segment at a time, so a
+// dotted field list works whether or not the document was flattened.
+func lookupPath(data map[string]interface{}, path string) (interface{}, bool) {
+	if v, ok := data[path]; ok {
+		return v, true
+	}
+
+	var cur interface{} = data
+	for _, part := range strings.Split(path, ".") {
+		m, ok := cur.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		cur, ok = m[part]
+		if !ok {
+			return nil, false
+		}
+	}
+	return cur, true
+}
+
+// projectFields restricts data to fields, resolving each as a dotted
+// path, or returns data unchanged when fields is empty.
+func projectFields(data map[string]interface{}, fields []string) map[string]interface{} {
+	if len(fields) == 0 {
+		return data
+	}
+	row := make(map[string]interface{}, len(fields))
+	for _, field := range fields {
+		if v, ok := lookupPath(data, field); ok {
+			row[field] = v
+		}
+	}
+	return row
+}
+
+// WriteNDJSON writes results as newline-delimited JSON, one object
+// per line, projected to fields (dotted paths resolved through
+// _source) when fields is non-empty, or the full document otherwise.
+// When flatten is set, nested objects are collapsed into dot-keyed
+// fields before projection.
+func WriteNDJSON(out io.Writer, results []Result, fields []string, flatten bool) error {
+	enc := json.NewEncoder(out)
+	for _, result := range results {
+		data, err := resultData(result)
+		if err != nil {
+			return err
+		}
+		if flatten {
+			data = flattenDoc(data)
+		}
+		if err := enc.Encode(projectFields(data, fields)); err != nil {
+			return errors.Annotate(err, "could not encode ndjson result")
+		}
+	}
+	return nil
+}
+
+// WriteCSV writes results as RFC 4180 CSV: a header row of fields
+// followed by one row per result, with each field resolved as a
+// dotted path into the result document. fields must be non-empty,
+// since a CSV header requires a fixed column set. When flatten is
+// set, nested objects are collapsed into dot-keyed fields before the
+// field lookup.
+func WriteCSV(out io.Writer, results []Result, fields []string, flatten bool) error {
+	if len(fields) == 0 {
+		return errors.New("csv output requires a field list (-Qc)")
+	}
+
+	w := csv.NewWriter(out)
+	if err := w.Write(fields); err != nil {
+		return errors.Annotate(err, "could not write csv header")
+	}
+
+	for _, result := range results {
+		data, err := resultData(result)
+		if err != nil {
+			return err
+		}
+		if flatten {
+			data = flattenDoc(data)
+		}
+
+		row := make([]string, len(fields))
+		for i, field := range fields {
+			if v, ok := lookupPath(data, field); ok {
+				row[i] = csvCell(v)
+			}
+		}
+		if err := w.Write(row); err != nil {
+			return errors.Annotate(err, "could not write csv row")
+		}
+	}
+
+	w.Flush()
+	return w.Error()
+}
+
+// csvCell renders a projected field value as a CSV cell: nil as an
+// empty cell, a fmt.Stringer via String, and anything else that isn't
+// already a plain string marshaled as JSON.
+func csvCell(v interface{}) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return t
+	case fmt.Stringer:
+		return t.String()
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprintf("%v", v)
+		}
+		return string(b)
+	}
+}
@@ -0,0 +1,159 @@
+package lgrep
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFlattenDoc(t *testing.T) {
+	data := map[string]interface{}{
+		"message": "boom",
+		"http": map[string]interface{}{
+			"method": "GET",
+			"response": map[string]interface{}{
+				"status_code": float64(500),
+			},
+		},
+	}
+
+	got := flattenDoc(data)
+	want := map[string]interface{}{
+		"message":                   "boom",
+		"http.method":               "GET",
+		"http.response.status_code": float64(500),
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("flattenDoc = %#v, want %#v", got, want)
+	}
+}
+
+func TestLookupPath(t *testing.T) {
+	data := map[string]interface{}{
+		"http": map[string]interface{}{
+			"method": "GET",
+		},
+	}
+
+	if v, ok := lookupPath(data, "http.method"); !ok || v != "GET" {
+		t.Errorf("lookupPath(http.method) = (%v, %v), want (GET, true)", v, ok)
+	}
+	if _, ok := lookupPath(data, "http.status"); ok {
+		t.Error("lookupPath should report ok=false for a missing nested field")
+	}
+	if _, ok := lookupPath(data, "http.method.extra"); ok {
+		t.Error("lookupPath should report ok=false when descending into a non-map value")
+	}
+}
+
+func TestProjectFields(t *testing.T) {
+	data := map[string]interface{}{"a": 1, "b": 2, "c": 3}
+
+	if got := projectFields(data, nil); !reflect.DeepEqual(got, data) {
+		t.Errorf("projectFields with no fields = %#v, want data unchanged", got)
+	}
+
+	got := projectFields(data, []string{"a", "c", "missing"})
+	want := map[string]interface{}{"a": 1, "c": 3}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("projectFields = %#v, want %#v", got, want)
+	}
+}
+
+func TestWriteNDJSONFlattenWithDottedFields(t *testing.T) {
+	results := []Result{
+		SourceResult(`{"service":"web","http":{"response":{"status_code":500}}}`),
+	}
+
+	var buf bytes.Buffer
+	if err := WriteNDJSON(&buf, results, []string{"service", "http.response.status_code"}, true); err != nil {
+		t.Fatalf("WriteNDJSON returned error: %v", err)
+	}
+
+	var row map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &row); err != nil {
+		t.Fatalf("could not decode ndjson line: %v", err)
+	}
+	want := map[string]interface{}{"service": "web", "http.response.status_code": float64(500)}
+	if !reflect.DeepEqual(row, want) {
+		t.Errorf("ndjson row = %#v, want %#v", row, want)
+	}
+}
+
+func TestWriteNDJSON(t *testing.T) {
+	results := []Result{
+		SourceResult(`{"service":"web","http":{"method":"GET"}}`),
+		SourceResult(`{"service":"db","http":{"method":"POST"}}`),
+	}
+
+	var buf bytes.Buffer
+	if err := WriteNDJSON(&buf, results, []string{"service", "http.method"}, false); err != nil {
+		t.Fatalf("WriteNDJSON returned error: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("WriteNDJSON wrote %d lines, want 2", len(lines))
+	}
+
+	var row map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[0]), &row); err != nil {
+		t.Fatalf("could not decode ndjson line: %v", err)
+	}
+	want := map[string]interface{}{"service": "web", "http.method": "GET"}
+	if !reflect.DeepEqual(row, want) {
+		t.Errorf("ndjson row = %#v, want %#v", row, want)
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	results := []Result{
+		SourceResult(`{"service":"web","message":"hello, \"world\""}`),
+	}
+
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, results, []string{"service", "message"}, false); err != nil {
+		t.Fatalf("WriteCSV returned error: %v", err)
+	}
+
+	// Round-trip through encoding/csv's own reader rather than
+	// asserting on the raw quoted/escaped bytes, so the test doesn't
+	// depend on exactly how encoding/csv escapes a field.
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("could not parse written csv: %v", err)
+	}
+	want := [][]string{
+		{"service", "message"},
+		{"web", `hello, "world"`},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("WriteCSV rows = %#v, want %#v", rows, want)
+	}
+}
+
+func TestWriteCSVRequiresFields(t *testing.T) {
+	if err := WriteCSV(&bytes.Buffer{}, nil, nil, false); err == nil {
+		t.Error("WriteCSV should require a non-empty field list")
+	}
+}
+
+func TestFlattenResult(t *testing.T) {
+	result := SourceResult(`{"http":{"method":"GET"}}`)
+
+	flat, err := FlattenResult(result)
+	if err != nil {
+		t.Fatalf("FlattenResult returned error: %v", err)
+	}
+
+	data, err := resultData(flat)
+	if err != nil {
+		t.Fatalf("resultData(flattened) returned error: %v", err)
+	}
+	if data["http.method"] != "GET" {
+		t.Errorf("flattened result missing http.method, got %#v", data)
+	}
+}
@@ -0,0 +1,32 @@
+package lgrep
+
+import (
+	"encoding/json"
+
+	"gopkg.in/olivere/elastic.v3"
+)
+
+// QueryMap is a raw, pre-built Elasticsearch query body expressed as
+// a map, used when a query cannot easily be composed with the
+// elastic.v3 query builders.
+type QueryMap map[string]interface{}
+
+// Source implements elastic.Query by returning the map as-is.
+func (q QueryMap) Source() (interface{}, error) {
+	return map[string]interface{}(q), nil
+}
+
+// QueryMapFromJSON decodes a raw JSON query body into a QueryMap.
+func QueryMapFromJSON(raw json.RawMessage) (QueryMap, error) {
+	var q QueryMap
+	if err := json.Unmarshal(raw, &q); err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+// SearchWithLucene applies a lucene query_string query to the given
+// search.
+func SearchWithLucene(search *elastic.SearchService, q string) *elastic.SearchService {
+	return search.Query(elastic.NewQueryStringQuery(q))
+}
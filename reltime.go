@@ -0,0 +1,53 @@
+package lgrep
+
+import (
+	"regexp"
+	"time"
+
+	"github.com/juju/errors"
+)
+
+// relativeTimeExpr matches Kibana/Grafana-style relative time
+// expressions: "now", "now-1h", "now+15m", "now-2d".
+var relativeTimeExpr = regexp.MustCompile(`^now(?:([+-])(\d+)([smhd]))?$`)
+
+// ParseTime parses a time expression as either RFC3339
+// ("2016-04-29T13:58:59Z") or a relative expression anchored to the
+// current time ("now", "now-1h", "now-15m").
+func ParseTime(expr string) (time.Time, error) {
+	if m := relativeTimeExpr.FindStringSubmatch(expr); m != nil {
+		return applyRelativeOffset(time.Now(), m[1], m[2], m[3])
+	}
+
+	t, err := time.Parse(time.RFC3339, expr)
+	if err != nil {
+		return t, errors.Annotatef(err, "could not parse time %q as RFC3339 or a relative expression (now, now-1h, now-15m, ...)", expr)
+	}
+	return t, nil
+}
+
+// applyRelativeOffset shifts t by the parsed sign, amount, and unit,
+// as extracted by relativeTimeExpr.
+func applyRelativeOffset(t time.Time, sign, amount, unit string) (time.Time, error) {
+	if amount == "" {
+		return t, nil
+	}
+
+	// time.ParseDuration has no notion of days, so "d" is parsed as
+	// hours and multiplied out.
+	parseUnit := unit
+	if unit == "d" {
+		parseUnit = "h"
+	}
+	n, err := time.ParseDuration(amount + parseUnit)
+	if err != nil {
+		return t, errors.Annotatef(err, "could not parse relative time offset %q%s", amount, unit)
+	}
+	if unit == "d" {
+		n *= 24
+	}
+	if sign == "-" {
+		n = -n
+	}
+	return t.Add(n), nil
+}
@@ -0,0 +1,60 @@
+package lgrep
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseTimeRFC3339(t *testing.T) {
+	got, err := ParseTime("2016-04-29T13:58:59Z")
+	if err != nil {
+		t.Fatalf("ParseTime returned error: %v", err)
+	}
+	want := time.Date(2016, 4, 29, 13, 58, 59, 0, time.UTC)
+	if !got.Equal(want) {
+		t.Errorf("ParseTime = %v, want %v", got, want)
+	}
+}
+
+func TestParseTimeInvalid(t *testing.T) {
+	if _, err := ParseTime("not a time"); err == nil {
+		t.Error("ParseTime should have returned an error for an unparseable expression")
+	}
+}
+
+func TestParseTimeRelative(t *testing.T) {
+	cases := []struct {
+		expr string
+		want time.Duration
+	}{
+		{"now", 0},
+		{"now-1h", -time.Hour},
+		{"now+15m", 15 * time.Minute},
+		{"now-30s", -30 * time.Second},
+		{"now-2d", -48 * time.Hour},
+	}
+
+	for _, c := range cases {
+		before := time.Now()
+		got, err := ParseTime(c.expr)
+		if err != nil {
+			t.Errorf("ParseTime(%q) returned error: %v", c.expr, err)
+			continue
+		}
+		after := time.Now()
+
+		gotOffset := got.Sub(before)
+		// Allow a little slack for the time it took to parse between
+		// before and after being captured.
+		slack := after.Sub(before)
+		if diff := gotOffset - c.want; diff < -slack || diff > slack {
+			t.Errorf("ParseTime(%q) offset from now = %v, want ~%v", c.expr, gotOffset, c.want)
+		}
+	}
+}
+
+func TestParseTimeRelativeInvalid(t *testing.T) {
+	if _, err := ParseTime("now-1x"); err == nil {
+		t.Error("ParseTime should reject an unrecognized relative unit")
+	}
+}
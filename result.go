@@ -0,0 +1,25 @@
+package lgrep
+
+import (
+	"encoding/json"
+
+	"gopkg.in/olivere/elastic.v3"
+)
+
+// Result represents a single matched document as returned from
+// Elasticsearch, in whatever shape the search requested: the full
+// source, a selection of fields, or the raw hit.
+type Result interface{}
+
+// HitResult wraps the full elastic.SearchHit as returned by
+// Elasticsearch, used when SearchOptions.RawResult is set.
+type HitResult elastic.SearchHit
+
+// FieldResult holds only the fields requested via
+// SearchOptions.Fields, keyed by field name. This mirrors
+// elastic.SearchHit.Fields, which Elasticsearch returns as a single
+// value per field rather than a list.
+type FieldResult map[string]interface{}
+
+// SourceResult holds the raw `_source` document of a hit.
+type SourceResult json.RawMessage
@@ -0,0 +1,112 @@
+package lgrep
+
+import (
+	"github.com/juju/errors"
+	"gopkg.in/olivere/elastic.v3"
+)
+
+// SortOrder determines the direction documents are sorted in when
+// time based sorting is requested.
+type SortOrder string
+
+const (
+	// SortAsc sorts results from oldest to newest.
+	SortAsc SortOrder = "asc"
+	// SortDesc sorts results from newest to oldest.
+	SortDesc SortOrder = "desc"
+)
+
+const (
+	// ScrollBatchSize is the default number of hits requested per
+	// batch when a search is scrolled.
+	ScrollBatchSize = 1000
+	// ScrollThreshold is the Size above which a search automatically
+	// switches to scrolling, even when Scroll was not explicitly
+	// requested.
+	ScrollThreshold = 10000
+	// DefaultScrollKeepAlive is how long a scroll context is kept
+	// alive between batches by default.
+	DefaultScrollKeepAlive = "1m"
+)
+
+// SearchOptions configures how a search is built and executed,
+// independent of the query itself.
+type SearchOptions struct {
+	// Index restricts the search to the named index or indices
+	// (comma-separated). Left empty, all indices are searched.
+	Index string
+	// Size is the maximum number of results to return. See Scroll
+	// for requesting result sets larger than a single page.
+	Size int
+	// SortTime, when set, sorts results by @timestamp in the given
+	// order. TimeField does not change the sort field.
+	SortTime SortOrder
+	// Fields restricts the returned document to only these fields.
+	Fields []string
+	// RawResult causes the raw elastic.SearchHit to be returned
+	// instead of just its source document.
+	RawResult bool
+	// QueryDebug prints the query that will be submitted to stderr.
+	QueryDebug bool
+	// QuerySkipValidate skips the validate-before-execute step.
+	QuerySkipValidate bool
+
+	// Scroll requests that results be retrieved with
+	// Elasticsearch's scroll API rather than a single request. This
+	// is implied automatically once Size exceeds ScrollThreshold,
+	// but can be set directly (e.g. from a CLI `--all` flag) to
+	// stream an entire matching result set regardless of Size.
+	Scroll bool
+	// ScrollSize is the number of hits requested per batch when
+	// Scroll is in effect. Defaults to ScrollBatchSize.
+	ScrollSize int
+	// ScrollKeepAlive is how long Elasticsearch should keep the
+	// scroll context alive between batches. Defaults to
+	// DefaultScrollKeepAlive.
+	ScrollKeepAlive string
+
+	// TimeField is the document field compared against by
+	// SearchTimerange. Defaults to DefaultTimeField.
+	TimeField string
+	// IndexPattern is the Go reference-time layout used by
+	// SearchTimerange to expand a time range into concrete indices
+	// (see resolveIndices). Defaults to DefaultIndexPattern.
+	IndexPattern string
+
+	// Aggregations requests an aggregation instead of document hits,
+	// in the shorthand syntax accepted by
+	// ParseAggregation (`terms:field`, `stats:field`,
+	// `date_histogram:field:interval`, nested with `+`) or as a raw
+	// JSON aggregation body. When set, Size is forced to 0 so only
+	// the aggregation result is computed.
+	Aggregations string
+}
+
+// configureSearch applies the options to the given search service.
+func (spec SearchOptions) configureSearch(search *elastic.SearchService) error {
+	search.Size(spec.Size)
+	if spec.Index != "" {
+		search.Index(spec.Index)
+	}
+	if len(spec.Fields) != 0 {
+		search.Fields(spec.Fields...)
+	}
+	if spec.SortTime != "" {
+		search.Sort("@timestamp", spec.SortTime == SortAsc)
+	}
+	if spec.Aggregations != "" {
+		name, _, agg, err := ParseAggregation(spec.Aggregations)
+		if err != nil {
+			return errors.Annotate(err, "invalid aggregation")
+		}
+		search.Aggregation(name, agg)
+		search.Size(0)
+	}
+	return nil
+}
+
+// useScroll reports whether the search should be paged in with the
+// scroll API rather than issued as a single request.
+func (spec SearchOptions) useScroll() bool {
+	return spec.Scroll || spec.Size > ScrollThreshold || spec.Size < 0
+}
@@ -0,0 +1,165 @@
+package lgrep
+
+import (
+	"io"
+
+	"github.com/juju/errors"
+	"gopkg.in/olivere/elastic.v3"
+)
+
+// SearchStream carries results from an in-progress search, paging
+// through Elasticsearch's scroll API transparently when the search
+// was configured to do so. Consumers read from Results until it is
+// closed, checking Errors for anything that went wrong along the
+// way.
+type SearchStream struct {
+	// Results delivers each document as it becomes available. It is
+	// closed once the search is exhausted or an error is sent on
+	// Errors.
+	Results chan Result
+	// Errors delivers any error encountered while streaming. At most
+	// one error is ever sent.
+	Errors chan error
+
+	close func() error
+}
+
+// All drains the stream and returns every result, blocking until the
+// stream is exhausted or an error occurs.
+func (s *SearchStream) All() (results []Result, err error) {
+	if s == nil {
+		return nil, nil
+	}
+	defer s.Close()
+
+	for r := range s.Results {
+		results = append(results, r)
+	}
+	select {
+	case err = <-s.Errors:
+	default:
+	}
+	return results, err
+}
+
+// Close releases any resources, such as an open scroll context, held
+// by the stream. It is safe to call multiple times.
+func (s *SearchStream) Close() error {
+	if s == nil || s.close == nil {
+		return nil
+	}
+	closeFn := s.close
+	s.close = nil
+	return closeFn()
+}
+
+// execute runs the configured search, returning a stream of results.
+// Once spec.useScroll() is true the results are paged in with
+// Elasticsearch's scroll API instead of a single request.
+func (l LGrep) execute(search *elastic.SearchService, query sourcer, spec SearchOptions) (*SearchStream, error) {
+	if spec.useScroll() {
+		return l.executeScroll(query, spec)
+	}
+
+	res, err := search.Do()
+	if err != nil {
+		return nil, errors.Annotate(err, "search request failed")
+	}
+
+	results, err := consumeResults(res, spec)
+
+	stream := &SearchStream{
+		Results: make(chan Result, len(results)),
+		Errors:  make(chan error, 1),
+	}
+	for _, r := range results {
+		stream.Results <- r
+	}
+	close(stream.Results)
+	if err != nil {
+		stream.Errors <- err
+	}
+	return stream, nil
+}
+
+// executeScroll pages through results using Elasticsearch's scroll
+// API, emitting each batch onto the stream as it arrives in pages of
+// spec.ScrollSize hits (ScrollBatchSize when unset) and clearing the
+// scroll context once
+// the stream is drained, closed, or an error occurs.
+func (l LGrep) executeScroll(query sourcer, spec SearchOptions) (*SearchStream, error) {
+	batchSize := spec.ScrollSize
+	if batchSize <= 0 {
+		batchSize = ScrollBatchSize
+	}
+	keepAlive := spec.ScrollKeepAlive
+	if keepAlive == "" {
+		keepAlive = DefaultScrollKeepAlive
+	}
+
+	src, err := query.Source()
+	if err != nil {
+		return nil, errors.Annotate(err, "could not generate query source for scroll")
+	}
+	// query's source already has "size": spec.Size baked in from
+	// spec.configureSearch, which Elasticsearch would read over
+	// Scroll.Size's query parameter since a body is present. Each
+	// scroll page must be sized by batchSize, not spec.Size, so
+	// override it in the body directly.
+	if body, ok := src.(map[string]interface{}); ok {
+		body["size"] = batchSize
+	}
+
+	scroll := l.Client.Scroll().Size(batchSize).KeepAlive(keepAlive).Body(src)
+	if spec.Index != "" {
+		scroll = scroll.Index(spec.Index)
+	}
+
+	stream := &SearchStream{
+		Results: make(chan Result, batchSize),
+		Errors:  make(chan error, 1),
+	}
+
+	var scrollID string
+	stream.close = func() error {
+		if scrollID == "" {
+			return nil
+		}
+		id := scrollID
+		scrollID = ""
+		_, err := l.Client.ClearScroll(id).Do()
+		return err
+	}
+
+	go func() {
+		defer close(stream.Results)
+		defer stream.Close()
+
+		for {
+			res, err := scroll.ScrollId(scrollID).Do()
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				stream.Errors <- errors.Annotate(err, "scroll request failed")
+				return
+			}
+			scrollID = res.ScrollId
+
+			if len(res.Hits.Hits) == 0 {
+				return
+			}
+
+			results, err := consumeResults(res, spec)
+			for _, r := range results {
+				stream.Results <- r
+			}
+			if err != nil {
+				stream.Errors <- err
+				return
+			}
+		}
+	}()
+
+	return stream, nil
+}
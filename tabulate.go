@@ -0,0 +1,158 @@
+package lgrep
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/juju/errors"
+	"gopkg.in/olivere/elastic.v3"
+)
+
+// Bucket represents a single bucket (or, for a single-value metric
+// aggregation like stats, its one result) from an aggregation,
+// normalized so it can be rendered the same way regardless of which
+// aggregation produced it.
+type Bucket struct {
+	// Key identifies the bucket, e.g. a terms value or a histogram
+	// interval's start.
+	Key interface{}
+	// DocCount is the number of documents that fell into the bucket.
+	DocCount int64
+	// Metrics holds any sub-metrics computed for the bucket (e.g.
+	// stats' min/max/avg/sum), keyed by metric name.
+	Metrics map[string]interface{}
+}
+
+// consumeAggregations extracts the buckets of the named, top-level
+// aggregation out of a search response. When the aggregation has a
+// nested sub-aggregation (subName/subKind, from SubAggregation), each
+// bucket's Metrics is populated from that sub-aggregation.
+func consumeAggregations(res *elastic.SearchResult, name, kind, subName, subKind string) (results []Result, err error) {
+	switch kind {
+	case "terms":
+		agg, found := res.Aggregations.Terms(name)
+		if !found {
+			return nil, errors.Errorf("no %q aggregation in the response", name)
+		}
+		for _, bucket := range agg.Buckets {
+			results = append(results, Bucket{
+				Key:      bucket.Key,
+				DocCount: bucket.DocCount,
+				Metrics:  bucketMetrics(bucket.Aggregations, subName, subKind),
+			})
+		}
+	case "date_histogram":
+		agg, found := res.Aggregations.DateHistogram(name)
+		if !found {
+			return nil, errors.Errorf("no %q aggregation in the response", name)
+		}
+		for _, bucket := range agg.Buckets {
+			key := bucket.Key
+			var keyVal interface{} = key
+			if bucket.KeyAsString != nil {
+				keyVal = *bucket.KeyAsString
+			}
+			results = append(results, Bucket{
+				Key:      keyVal,
+				DocCount: bucket.DocCount,
+				Metrics:  bucketMetrics(bucket.Aggregations, subName, subKind),
+			})
+		}
+	case "stats":
+		agg, found := res.Aggregations.Stats(name)
+		if !found {
+			return nil, errors.Errorf("no %q aggregation in the response", name)
+		}
+		results = append(results, Bucket{
+			Key:      name,
+			DocCount: agg.Count,
+			Metrics: map[string]interface{}{
+				"min": agg.Min,
+				"max": agg.Max,
+				"avg": agg.Avg,
+				"sum": agg.Sum,
+			},
+		})
+	default:
+		return nil, errors.Errorf("aggregation kind %q cannot be rendered yet", kind)
+	}
+	return results, nil
+}
+
+// bucketMetrics extracts the named sub-aggregation of kind subKind
+// out of a bucket's own nested aggregations, in the key/value shape
+// Bucket.Metrics expects. It returns nil (no metrics) when subName is
+// empty or the sub-aggregation isn't present.
+func bucketMetrics(aggs elastic.Aggregations, subName, subKind string) map[string]interface{} {
+	if subName == "" {
+		return nil
+	}
+	switch subKind {
+	case "stats":
+		agg, found := aggs.Stats(subName)
+		if !found {
+			return nil
+		}
+		return map[string]interface{}{
+			"min": agg.Min,
+			"max": agg.Max,
+			"avg": agg.Avg,
+			"sum": agg.Sum,
+		}
+	case "terms":
+		agg, found := aggs.Terms(subName)
+		if !found {
+			return nil
+		}
+		metrics := make(map[string]interface{}, len(agg.Buckets))
+		for _, bucket := range agg.Buckets {
+			metrics[fmt.Sprintf("%v", bucket.Key)] = bucket.DocCount
+		}
+		return metrics
+	case "date_histogram":
+		agg, found := aggs.DateHistogram(subName)
+		if !found {
+			return nil
+		}
+		metrics := make(map[string]interface{}, len(agg.Buckets))
+		for _, bucket := range agg.Buckets {
+			key := fmt.Sprintf("%v", bucket.Key)
+			if bucket.KeyAsString != nil {
+				key = *bucket.KeyAsString
+			}
+			metrics[key] = bucket.DocCount
+		}
+		return metrics
+	default:
+		return nil
+	}
+}
+
+// Tabulate writes results as a tab-aligned table of key, doc_count,
+// and any sub-metrics - one row per bucket. Results that aren't
+// Buckets (e.g. ordinary document hits) are rejected, since there's
+// no single sensible column layout for an arbitrary document.
+func Tabulate(out io.Writer, results []Result) error {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	for _, result := range results {
+		bucket, ok := result.(Bucket)
+		if !ok {
+			return errors.Errorf("cannot tabulate result of type %T", result)
+		}
+
+		metricKeys := make([]string, 0, len(bucket.Metrics))
+		for k := range bucket.Metrics {
+			metricKeys = append(metricKeys, k)
+		}
+		sort.Strings(metricKeys)
+
+		row := fmt.Sprintf("%v\t%d", bucket.Key, bucket.DocCount)
+		for _, k := range metricKeys {
+			row += fmt.Sprintf("\t%s=%v", k, bucket.Metrics[k])
+		}
+		fmt.Fprintln(w, row)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,221 @@
+package lgrep
+
+import (
+	"container/list"
+	"context"
+	"time"
+
+	"github.com/juju/errors"
+)
+
+const (
+	// DefaultPollInterval is how often a tail re-queries
+	// Elasticsearch for newly matching documents.
+	DefaultPollInterval = 5 * time.Second
+	// DefaultTailOverlap is how far behind the last seen document a
+	// tail re-queries on every poll, to cover for clock skew between
+	// the documents' own timestamps and when they become visible to
+	// search.
+	DefaultTailOverlap = 2 * time.Second
+	// DefaultDedupeSize bounds how many recently emitted document
+	// IDs a tail remembers in order to avoid re-emitting a document
+	// that falls within the overlap window of more than one poll.
+	DefaultDedupeSize = 10000
+)
+
+// TailOptions configures the behavior of TailStream, independent of
+// the underlying search.
+type TailOptions struct {
+	// PollInterval is how often to re-query for new documents.
+	// Defaults to DefaultPollInterval.
+	PollInterval time.Duration
+	// Overlap is subtracted from the last seen timestamp on every
+	// poll to cushion against clock skew. Defaults to
+	// DefaultTailOverlap.
+	Overlap time.Duration
+	// DedupeSize bounds the number of recently emitted document IDs
+	// remembered to drop duplicates produced by Overlap. Defaults to
+	// DefaultDedupeSize.
+	DedupeSize int
+}
+
+// DefaultTailOptions provides a reasonable default tail
+// configuration.
+var DefaultTailOptions = TailOptions{
+	PollInterval: DefaultPollInterval,
+	Overlap:      DefaultTailOverlap,
+	DedupeSize:   DefaultDedupeSize,
+}
+
+// TailStream behaves like SimpleSearchStream, but rather than
+// returning once the matching documents have been read, it polls for
+// newly matching documents as they arrive and streams them as they're
+// found, much like `tail -f`. The stream runs until ctx is canceled.
+func (l LGrep) TailStream(ctx context.Context, q string, spec *SearchOptions, topts *TailOptions) (*SearchStream, error) {
+	if q == "" {
+		return nil, ErrEmptySearch
+	}
+	if spec == nil {
+		spec = &DefaultSpec
+	}
+	if topts == nil {
+		topts = &DefaultTailOptions
+	}
+
+	timeField := spec.TimeField
+	if timeField == "" {
+		timeField = DefaultTimeField
+	}
+
+	stream := &SearchStream{
+		Results: make(chan Result, spec.Size),
+		Errors:  make(chan error, 1),
+	}
+	stream.close = func() error { return nil }
+
+	go l.tailLoop(ctx, stream, q, *spec, timeField, *topts)
+
+	return stream, nil
+}
+
+// tailLoop repeatedly polls for documents newer than the last one
+// seen, feeding them onto stream.Results until ctx is canceled.
+func (l LGrep) tailLoop(ctx context.Context, stream *SearchStream, q string, spec SearchOptions, timeField string, topts TailOptions) {
+	defer close(stream.Results)
+
+	seen := newBoundedSet(topts.DedupeSize)
+	since := time.Now().Add(-topts.Overlap)
+
+	ticker := time.NewTicker(topts.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		newest, err := l.tailPoll(stream, q, spec, timeField, since, seen)
+		if err != nil {
+			select {
+			case stream.Errors <- err:
+			default:
+			}
+			return
+		}
+		if newest.After(since) {
+			since = newest.Add(-topts.Overlap)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// tailPoll issues a single sorted query for documents at or after
+// since, emitting any not already in seen and returning the newest
+// timestamp observed. Pagination across a poll's matches, and
+// deduplication across overlapping polls, both rely on seen rather
+// than Elasticsearch's search_after: gopkg.in/olivere/elastic.v3 only
+// speaks Elasticsearch 2.x, and search_after isn't available until
+// Elasticsearch 5 (see the BackendVersion doc comment in backend.go),
+// so tie-breaking among documents with an identical timeField value
+// is approximate under this client.
+func (l LGrep) tailPoll(stream *SearchStream, q string, spec SearchOptions, timeField string, since time.Time, seen *boundedSet) (time.Time, error) {
+	newest := since
+
+	backend := l.Backend
+	if backend == nil {
+		backend = backendV2{}
+	}
+
+	search, _ := l.NewSearch()
+	query := backend.BoolQuery(
+		[]QueryMap{queryStringQueryMap(q)},
+		[]QueryMap{rangeQueryMap(timeField, since, nil)},
+	)
+	search = search.Query(query).Sort(timeField, true).Sort("_id", true)
+	spec.configureSearch(search)
+
+	res, err := search.Do()
+	if err != nil {
+		return newest, errors.Annotate(err, "tail poll failed")
+	}
+
+	for _, hit := range res.Hits.Hits {
+		if seen.Contains(hit.Id) {
+			continue
+		}
+		seen.Add(hit.Id)
+
+		result, err := extractResult(hit, spec)
+		if err != nil {
+			return newest, err
+		}
+		stream.Results <- result
+
+		if t, ok := sortTime(hit.Sort); ok && t.After(newest) {
+			newest = t
+		}
+	}
+
+	return newest, nil
+}
+
+// sortTime extracts the time a hit was sorted on from its first sort
+// value. Elasticsearch's 2.x wire protocol (spoken by
+// gopkg.in/olivere/elastic.v3) encodes a date sort value as epoch
+// milliseconds, not a formatted string - the per-sort "format" option
+// that would make this a string doesn't exist until 6.4+ - so it
+// decodes from JSON as a float64. A comma-ok assertion lets a
+// malformed or missing sort value fall through to the caller's
+// existing `since` instead of panicking.
+func sortTime(sort []interface{}) (time.Time, bool) {
+	if len(sort) == 0 {
+		return time.Time{}, false
+	}
+	ms, ok := sort[0].(float64)
+	if !ok {
+		return time.Time{}, false
+	}
+	return time.Unix(0, int64(ms)*int64(time.Millisecond)), true
+}
+
+// boundedSet remembers a fixed number of recently seen keys,
+// evicting the oldest once it's full.
+type boundedSet struct {
+	capacity int
+	order    *list.List
+	elements map[string]*list.Element
+}
+
+func newBoundedSet(capacity int) *boundedSet {
+	if capacity <= 0 {
+		capacity = DefaultDedupeSize
+	}
+	return &boundedSet{
+		capacity: capacity,
+		order:    list.New(),
+		elements: make(map[string]*list.Element, capacity),
+	}
+}
+
+// Contains reports whether key was added since it was last evicted.
+func (s *boundedSet) Contains(key string) bool {
+	_, ok := s.elements[key]
+	return ok
+}
+
+// Add records key as seen, evicting the oldest entry if the set is
+// at capacity.
+func (s *boundedSet) Add(key string) {
+	if s.Contains(key) {
+		return
+	}
+	if s.order.Len() >= s.capacity {
+		oldest := s.order.Front()
+		if oldest != nil {
+			s.order.Remove(oldest)
+			delete(s.elements, oldest.Value.(string))
+		}
+	}
+	s.elements[key] = s.order.PushBack(key)
+}
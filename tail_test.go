@@ -0,0 +1,75 @@
+package lgrep
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBoundedSetAddContains(t *testing.T) {
+	s := newBoundedSet(2)
+
+	if s.Contains("a") {
+		t.Fatal("new boundedSet should not contain anything yet")
+	}
+
+	s.Add("a")
+	if !s.Contains("a") {
+		t.Error("boundedSet should contain a key after Add")
+	}
+}
+
+func TestBoundedSetEvictsOldest(t *testing.T) {
+	s := newBoundedSet(2)
+
+	s.Add("a")
+	s.Add("b")
+	s.Add("c")
+
+	if s.Contains("a") {
+		t.Error("boundedSet should have evicted its oldest entry once over capacity")
+	}
+	if !s.Contains("b") || !s.Contains("c") {
+		t.Error("boundedSet should still contain its two most recent entries")
+	}
+}
+
+func TestBoundedSetAddIsIdempotent(t *testing.T) {
+	s := newBoundedSet(2)
+
+	s.Add("a")
+	s.Add("b")
+	s.Add("a") // re-adding "a" should not bump "b" out.
+
+	if !s.Contains("a") || !s.Contains("b") {
+		t.Error("re-adding an existing key should not evict another entry")
+	}
+}
+
+func TestBoundedSetDefaultsCapacity(t *testing.T) {
+	s := newBoundedSet(0)
+	if s.capacity != DefaultDedupeSize {
+		t.Errorf("newBoundedSet(0).capacity = %d, want %d", s.capacity, DefaultDedupeSize)
+	}
+}
+
+func TestSortTime(t *testing.T) {
+	want := time.Date(2016, 4, 29, 13, 58, 59, 0, time.UTC)
+	ms := float64(want.UnixNano() / int64(time.Millisecond))
+
+	got, ok := sortTime([]interface{}{ms, "some-id"})
+	if !ok {
+		t.Fatal("sortTime should report ok=true for a float64 sort value")
+	}
+	if !got.Equal(want) {
+		t.Errorf("sortTime = %v, want %v", got, want)
+	}
+}
+
+func TestSortTimeInvalid(t *testing.T) {
+	if _, ok := sortTime(nil); ok {
+		t.Error("sortTime should report ok=false for an empty sort slice")
+	}
+	if _, ok := sortTime([]interface{}{"not-a-float"}); ok {
+		t.Error("sortTime should report ok=false when the sort value isn't a float64")
+	}
+}
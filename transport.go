@@ -0,0 +1,145 @@
+package lgrep
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/juju/errors"
+)
+
+// AuthOptions configures how lgrep authenticates to and trusts the
+// Elasticsearch/OpenSearch cluster it talks to.
+type AuthOptions struct {
+	// Username and Password enable HTTP basic auth.
+	Username string
+	Password string
+	// APIKey enables Elasticsearch API key auth, sent as
+	// `Authorization: ApiKey <APIKey>`.
+	APIKey string
+	// BearerToken enables bearer token auth, sent as
+	// `Authorization: Bearer <BearerToken>`.
+	BearerToken string
+
+	// CACertFile, if set, is used instead of the system trust store
+	// to verify the server's certificate.
+	CACertFile string
+	// ClientCertFile and ClientKeyFile, if both set, enable mutual
+	// TLS by presenting this client certificate.
+	ClientCertFile string
+	ClientKeyFile  string
+	// InsecureSkipVerify disables TLS certificate verification
+	// entirely. Only use against clusters you already trust by other
+	// means (e.g. local development).
+	InsecureSkipVerify bool
+
+	// CloudID, if set, is decoded into the cluster's endpoint,
+	// overriding whatever endpoint was otherwise given.
+	CloudID string
+}
+
+// newHTTPClient builds the *http.Client used to talk to
+// Elasticsearch, with TLS configured per auth and an
+// Authorization header injected per request.
+func newHTTPClient(auth AuthOptions) (*http.Client, error) {
+	tlsConfig, err := newTLSConfig(auth)
+	if err != nil {
+		return nil, err
+	}
+
+	return &http.Client{
+		Transport: &authRoundTripper{
+			base:        &http.Transport{TLSClientConfig: tlsConfig},
+			username:    auth.Username,
+			password:    auth.Password,
+			apiKey:      auth.APIKey,
+			bearerToken: auth.BearerToken,
+		},
+	}, nil
+}
+
+// newTLSConfig builds the *tls.Config described by auth, returning
+// nil when no TLS options were given (letting callers fall back to
+// Go's defaults).
+func newTLSConfig(auth AuthOptions) (*tls.Config, error) {
+	if auth.CACertFile == "" && auth.ClientCertFile == "" && !auth.InsecureSkipVerify {
+		return nil, nil
+	}
+
+	config := &tls.Config{InsecureSkipVerify: auth.InsecureSkipVerify}
+
+	if auth.CACertFile != "" {
+		pem, err := ioutil.ReadFile(auth.CACertFile)
+		if err != nil {
+			return nil, errors.Annotate(err, "could not read CA certificate")
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, errors.Errorf("no certificates found in %s", auth.CACertFile)
+		}
+		config.RootCAs = pool
+	}
+
+	if auth.ClientCertFile != "" {
+		if auth.ClientKeyFile == "" {
+			return nil, errors.New("a client key file is required alongside a client certificate")
+		}
+		cert, err := tls.LoadX509KeyPair(auth.ClientCertFile, auth.ClientKeyFile)
+		if err != nil {
+			return nil, errors.Annotate(err, "could not load client certificate/key")
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	return config, nil
+}
+
+// authRoundTripper injects an Authorization header into every
+// request before delegating to base.
+type authRoundTripper struct {
+	base        http.RoundTripper
+	username    string
+	password    string
+	apiKey      string
+	bearerToken string
+}
+
+func (rt *authRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+
+	switch {
+	case rt.apiKey != "":
+		req.Header.Set("Authorization", "ApiKey "+rt.apiKey)
+	case rt.bearerToken != "":
+		req.Header.Set("Authorization", "Bearer "+rt.bearerToken)
+	case rt.username != "":
+		req.SetBasicAuth(rt.username, rt.password)
+	}
+
+	return rt.base.RoundTrip(req)
+}
+
+// ParseCloudID decodes an Elastic Cloud ID ("deployment:base64(host$es_uuid$kibana_uuid)")
+// into the cluster's HTTPS endpoint.
+func ParseCloudID(cloudID string) (endpoint string, err error) {
+	parts := strings.SplitN(cloudID, ":", 2)
+	if len(parts) != 2 {
+		return "", errors.Errorf("invalid cloud id %q, expected \"name:base64\"", cloudID)
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", errors.Annotate(err, "could not decode cloud id")
+	}
+
+	fields := strings.Split(string(decoded), "$")
+	if len(fields) < 2 || fields[0] == "" || fields[1] == "" {
+		return "", errors.Errorf("invalid cloud id %q, expected \"name:base64(host$es_uuid$kibana_uuid)\"", cloudID)
+	}
+
+	return fmt.Sprintf("https://%s.%s", fields[1], fields[0]), nil
+}
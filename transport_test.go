@@ -0,0 +1,66 @@
+package lgrep
+
+import (
+	"testing"
+)
+
+func TestParseCloudID(t *testing.T) {
+	// base64("us-east-1.aws.found.io$myesuuid$mykibanauuid")
+	cloudID := "my-deployment:dXMtZWFzdC0xLmF3cy5mb3VuZC5pbyRteWVzdXVpZCRteWtpYmFuYXV1aWQ="
+
+	endpoint, err := ParseCloudID(cloudID)
+	if err != nil {
+		t.Fatalf("ParseCloudID returned error: %v", err)
+	}
+	want := "https://myesuuid.us-east-1.aws.found.io"
+	if endpoint != want {
+		t.Errorf("ParseCloudID = %q, want %q", endpoint, want)
+	}
+}
+
+func TestParseCloudIDInvalid(t *testing.T) {
+	cases := []string{
+		"no-colon-here",
+		"my-deployment:not-base64!!!",
+		"my-deployment:" + "aG9zdC1vbmx5", // base64("host-only"), missing the "$" separators
+	}
+	for _, cloudID := range cases {
+		if _, err := ParseCloudID(cloudID); err == nil {
+			t.Errorf("ParseCloudID(%q) should have returned an error", cloudID)
+		}
+	}
+}
+
+func TestNewTLSConfigNoOptions(t *testing.T) {
+	config, err := newTLSConfig(AuthOptions{})
+	if err != nil {
+		t.Fatalf("newTLSConfig returned error: %v", err)
+	}
+	if config != nil {
+		t.Errorf("newTLSConfig with no TLS options = %#v, want nil", config)
+	}
+}
+
+func TestNewTLSConfigInsecureSkipVerify(t *testing.T) {
+	config, err := newTLSConfig(AuthOptions{InsecureSkipVerify: true})
+	if err != nil {
+		t.Fatalf("newTLSConfig returned error: %v", err)
+	}
+	if config == nil || !config.InsecureSkipVerify {
+		t.Errorf("newTLSConfig = %#v, want InsecureSkipVerify=true", config)
+	}
+}
+
+func TestNewTLSConfigMissingClientKey(t *testing.T) {
+	_, err := newTLSConfig(AuthOptions{ClientCertFile: "cert.pem"})
+	if err == nil {
+		t.Error("newTLSConfig should require a client key file alongside a client certificate")
+	}
+}
+
+func TestNewTLSConfigMissingCACertFile(t *testing.T) {
+	_, err := newTLSConfig(AuthOptions{CACertFile: "/nonexistent/ca.pem"})
+	if err == nil {
+		t.Error("newTLSConfig should return an error when the CA certificate file can't be read")
+	}
+}